@@ -36,10 +36,13 @@ func main() {
 		w.Write([]byte("here meet psychic beams"))
 	})))
 
+	mux.Handle("/metrics", mtr.Handler())
+
 	wsHandler := psyche.NewWebsocketHandler(node)
 
 	mux.Handle("/psyche", metrics.InFlightMiddleware(
 		mtr.NewGauge("requests_in_flight", 0, 0, ""),
+		mtr.NewHistogram("request_duration_seconds", metrics.DefaultBuckets, 0, ""),
 		logRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			c := wsHandler.Accept(w, r)
 
@@ -61,13 +64,13 @@ func main() {
 
 	if err := http.Serve(metrics.SentReceivedMiddleware(
 		// Server-wide metrics
-		mtr.NewGauge("bytes_sent", time.Second, 128, ""),
-		mtr.NewGauge("bytes_received", time.Second, 0, ""),
+		mtr.NewCounter("bytes_sent", time.Second, 128, ""),
+		mtr.NewCounter("bytes_received", time.Second, 0, ""),
 
 		// Metrics specific to each connection
-		func(raddr string) (sent, received *metrics.Gauge) {
-			sent = mtr.NewGauge("bytes_sent{conn=this}", time.Second, 128, raddr)
-			received = mtr.NewGauge("bytes_received{conn=this}", time.Second, 0, raddr)
+		func(raddr string) (sent, received *metrics.Counter) {
+			sent = mtr.NewCounter("bytes_sent{conn=this}", time.Second, 128, raddr)
+			received = mtr.NewCounter("bytes_received{conn=this}", time.Second, 0, raddr)
 			return
 		},
 
@@ -0,0 +1,44 @@
+package metrics
+
+// ChanMsg is a single message delivered to a ChanSink.
+type ChanMsg struct {
+	Subject string
+	Payload []byte
+}
+
+// ChanSink is an in-process Sink backed by Go channels. It's meant for
+// tests: attach it to a Metrics, drain Published to see what was
+// published, and call TriggerPoll to request a snapshot on demand.
+type ChanSink struct {
+	Published chan ChanMsg
+
+	poll chan struct{}
+}
+
+// NewChanSink creates a ChanSink. Published must be drained by the
+// caller or Publish will block.
+func NewChanSink() *ChanSink {
+	return &ChanSink{
+		Published: make(chan ChanMsg, 16),
+		poll:      make(chan struct{}),
+	}
+}
+
+func (s *ChanSink) Publish(subject string, payload []byte) error {
+	s.Published <- ChanMsg{Subject: subject, Payload: payload}
+	return nil
+}
+
+func (s *ChanSink) Poll() <-chan struct{} {
+	return s.poll
+}
+
+// TriggerPoll requests a metrics snapshot, as if the underlying
+// transport had just received a poll request.
+func (s *ChanSink) TriggerPoll() {
+	s.poll <- struct{}{}
+}
+
+func (s *ChanSink) Close() {
+	close(s.poll)
+}
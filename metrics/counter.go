@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Counter struct {
+	name         string
+	val          int64
+	oncePerDur   *OncePerDur
+	oncePerDelta *OncePerDelta
+	raddr        string
+	m            *Metrics
+
+	closed int32
+
+	ttlMu    sync.Mutex
+	ttl      time.Duration
+	ttlTimer *time.Timer
+}
+
+// NewCounter creates a new counter.
+//
+// Safe to call from multiple goroutines.
+func (m *Metrics) NewCounter(name string, oncePerDur time.Duration, oncePerDelta int64, raddr string) *Counter {
+	c := &Counter{
+		name:         name,
+		oncePerDur:   NewOncePerDur(oncePerDur),
+		oncePerDelta: NewOncePerDelta(oncePerDelta),
+		raddr:        raddr,
+		m:            m,
+	}
+
+	m.countersMu.Lock()
+	m.counters = append(m.counters, c)
+	m.countersMu.Unlock()
+
+	return c
+}
+
+func (c *Counter) Val() int64 {
+	return atomic.LoadInt64(&c.val)
+}
+
+// Type reports the Prometheus/OpenMetrics type of this series.
+func (c *Counter) Type() MetricType {
+	return TypeCounter
+}
+
+func (c *Counter) promName() string {
+	name, _ := splitNameLabels(c.name)
+	return name
+}
+
+func (c *Counter) StringWithVal(val int64) string {
+	return fmt.Sprintf("%s=%d", c.name, val)
+}
+
+// PromStringWithVal renders the counter's value as a single Prometheus/
+// OpenMetrics text exposition sample, e.g. `bytes_sent{conn="1.2.3.4:5"} 42`.
+func (c *Counter) PromStringWithVal(val int64) string {
+	name, labels := splitNameLabels(c.name)
+	return fmt.Sprintf("%s%s %d", name, formatPromLabels(promLabels(labels, c.raddr)), val)
+}
+
+func (c *Counter) pub(v int64) {
+	c.m.pub(c.raddr, []byte(c.StringWithVal(v)))
+}
+
+// Add increases the counter by n. Negative values are rejected since a
+// counter must only ever increase; use a Gauge for values that can go down.
+//
+// Safe to call from multiple goroutines.
+func (c *Counter) Add(n int64) {
+	if n <= 0 {
+		return
+	}
+	val := atomic.AddInt64(&c.val, n)
+
+	c.ttlMu.Lock()
+	if c.ttlTimer != nil {
+		c.ttlTimer.Reset(c.ttl)
+	}
+	c.ttlMu.Unlock()
+
+	if c.m.overloaded(c.raddr) {
+		return
+	}
+	c.oncePerDelta.Do(val, func() {
+		c.oncePerDur.Do(c.m.ctx, func() {
+			c.pub(val)
+		})
+	})
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// addSilent increases the counter without attempting to publish an
+// update, so callers on a publish path that could itself be dropped
+// (e.g. sinkQueue counting into metrics_dropped_total) can't re-enter
+// that same path. The value is still visible through Val and through
+// any poll of the counter in the usual way.
+//
+// Safe to call from multiple goroutines.
+func (c *Counter) addSilent(n int64) {
+	if n <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.val, n)
+}
+
+// SetTTL arms the counter to auto-Close if no Add call refreshes it
+// within d, unless a sink is still attached for the counter's raddr, in
+// which case it keeps rechecking every d instead. This is meant for
+// ephemeral per-connection counters that might otherwise never be
+// explicitly Closed.
+//
+// Safe to call from multiple goroutines.
+func (c *Counter) SetTTL(d time.Duration) {
+	c.ttlMu.Lock()
+	defer c.ttlMu.Unlock()
+
+	c.ttl = d
+	if c.ttlTimer == nil {
+		c.ttlTimer = time.AfterFunc(d, c.checkTTL)
+	} else {
+		c.ttlTimer.Reset(d)
+	}
+}
+
+func (c *Counter) checkTTL() {
+	c.ttlMu.Lock()
+	if c.raddr != "" && c.m.hasSink(c.raddr) {
+		c.ttlTimer.Reset(c.ttl)
+		c.ttlMu.Unlock()
+		return
+	}
+	c.ttlMu.Unlock()
+
+	c.Close()
+}
+
+// Close unregisters the counter, after publishing one final value so
+// subscribers see where it ended up. Safe to call more than once, and
+// safe to call concurrently with Add.
+func (c *Counter) Close() {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return
+	}
+
+	c.ttlMu.Lock()
+	if c.ttlTimer != nil {
+		c.ttlTimer.Stop()
+	}
+	c.ttlMu.Unlock()
+
+	c.pub(c.Val())
+	c.m.removeCounter(c)
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are histogram bucket boundaries suited to measuring
+// request latency in seconds, matching common Prometheus client defaults.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type Histogram struct {
+	name    string
+	buckets []float64 // upper bounds (le), ascending, not including +Inf
+
+	bucketCounts []int64 // per-bucket counts, same indices as buckets
+	count        int64
+	sumBits      uint64 // bit pattern of the running sum, see math.Float64bits
+
+	oncePerDur *OncePerDur
+	raddr      string
+	m          *Metrics
+}
+
+// NewHistogram creates a new histogram with the given bucket upper bounds
+// (le values). buckets must be sorted ascending; a final +Inf bucket is
+// implied.
+//
+// Safe to call from multiple goroutines.
+func (m *Metrics) NewHistogram(name string, buckets []float64, oncePerDur time.Duration, raddr string) *Histogram {
+	h := &Histogram{
+		name:         name,
+		buckets:      buckets,
+		bucketCounts: make([]int64, len(buckets)),
+		oncePerDur:   NewOncePerDur(oncePerDur),
+		raddr:        raddr,
+		m:            m,
+	}
+
+	m.histogramsMu.Lock()
+	m.histograms = append(m.histograms, h)
+	m.histogramsMu.Unlock()
+
+	return h
+}
+
+// Type reports the Prometheus/OpenMetrics type of this series.
+func (h *Histogram) Type() MetricType {
+	return TypeHistogram
+}
+
+func (h *Histogram) promName() string {
+	name, _ := splitNameLabels(h.name)
+	return name
+}
+
+func (h *Histogram) Sum() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&h.sumBits))
+}
+
+func (h *Histogram) Count() int64 {
+	return atomic.LoadInt64(&h.count)
+}
+
+// Observe records a single sample: it increments the narrowest bucket
+// whose boundary is >= v, and updates the running sum and count.
+//
+// Safe to call from multiple goroutines.
+func (h *Histogram) Observe(v float64) {
+	atomic.AddInt64(&h.count, 1)
+
+	for {
+		old := atomic.LoadUint64(&h.sumBits)
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sumBits, old, next) {
+			break
+		}
+	}
+
+	for i, le := range h.buckets {
+		if v <= le {
+			atomic.AddInt64(&h.bucketCounts[i], 1)
+			break
+		}
+	}
+
+	if h.m.overloaded(h.raddr) {
+		return
+	}
+	h.oncePerDur.Do(h.m.ctx, func() {
+		h.m.pub(h.raddr, []byte(h.pubString()))
+	})
+}
+
+// withLabel merges an extra label=value pair into raw's existing
+// "{...}" label suffix, following the same "name{a=b,c=d}" convention
+// used throughout this package.
+func withLabel(raw, label, value string) string {
+	name, labels := splitNameLabels(raw)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[label] = value
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+
+	return name + "{" + strings.Join(parts, ",") + "}"
+}
+
+// pubString renders the histogram's _bucket, _sum and _count series in
+// the psyche "name=value" convention, one per line.
+func (h *Histogram) pubString() string {
+	b := strings.Builder{}
+
+	name, _ := splitNameLabels(h.name)
+	bucketName := name + "_bucket" + h.name[len(name):]
+
+	cumulative := int64(0)
+	for i, le := range h.buckets {
+		cumulative += atomic.LoadInt64(&h.bucketCounts[i])
+		fmt.Fprintf(&b, "%s=%d\n", withLabel(bucketName, "le", formatFloat(le)), cumulative)
+	}
+	fmt.Fprintf(&b, "%s=%d\n", withLabel(bucketName, "le", "+Inf"), h.Count())
+
+	fmt.Fprintf(&b, "%s_sum=%v\n", name, h.Sum())
+	fmt.Fprintf(&b, "%s_count=%d", name, h.Count())
+
+	return b.String()
+}
+
+// promLines renders the histogram's _bucket, _sum and _count series as
+// Prometheus/OpenMetrics text exposition samples.
+func (h *Histogram) promLines() []string {
+	name, labels := splitNameLabels(h.name)
+	labels = promLabels(labels, h.raddr)
+
+	lines := make([]string, 0, len(h.buckets)+3)
+
+	cumulative := int64(0)
+	for i, le := range h.buckets {
+		cumulative += atomic.LoadInt64(&h.bucketCounts[i])
+		leLabels := formatPromLabels(mergeLabel(labels, "le", formatFloat(le)))
+		lines = append(lines, fmt.Sprintf("%s_bucket%s %d", name, leLabels, cumulative))
+	}
+	infLabels := formatPromLabels(mergeLabel(labels, "le", "+Inf"))
+	lines = append(lines, fmt.Sprintf("%s_bucket%s %d", name, infLabels, h.Count()))
+
+	baseLabels := formatPromLabels(labels)
+	lines = append(lines, fmt.Sprintf("%s_sum%s %v", name, baseLabels, h.Sum()))
+	lines = append(lines, fmt.Sprintf("%s_count%s %d", name, baseLabels, h.Count()))
+
+	return lines
+}
+
+func mergeLabel(labels map[string]string, k, v string) map[string]string {
+	merged := make(map[string]string, len(labels)+1)
+	for lk, lv := range labels {
+		merged[lk] = lv
+	}
+	merged[k] = v
+	return merged
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
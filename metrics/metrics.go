@@ -1,11 +1,11 @@
 package metrics
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,13 +18,108 @@ var (
 	metricsSubject = ".metrics"
 )
 
+// MetricType identifies the Prometheus/OpenMetrics type of a metric
+// series: gauge, counter or histogram.
+type MetricType string
+
+const (
+	TypeGauge     MetricType = "gauge"
+	TypeCounter   MetricType = "counter"
+	TypeHistogram MetricType = "histogram"
+)
+
+// splitNameLabels splits a metric name of the form "name{label=value,...}",
+// the convention used throughout this package, into its base name and
+// labels. Names without a "{...}" suffix are returned as-is with a nil map.
+func splitNameLabels(raw string) (name string, labels map[string]string) {
+	i := strings.IndexByte(raw, '{')
+	if i < 0 || !strings.HasSuffix(raw, "}") {
+		return raw, nil
+	}
+
+	name = raw[:i]
+	labels = map[string]string{}
+
+	for _, pair := range strings.Split(raw[i+1:len(raw)-1], ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		labels[kv[0]] = kv[1]
+	}
+
+	return name, labels
+}
+
+// formatPromLabels renders labels as a Prometheus/OpenMetrics label set,
+// e.g. `{conn="1.2.3.4:5"}`, or "" if there are none.
+func formatPromLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%q", k, labels[k])
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// promLabels resolves a metric's labels for Prometheus/OpenMetrics
+// rendering, substituting raddr for the literal label value "this".
+//
+// The "name{conn=this}" convention (see SentReceivedMiddleware) only ever
+// meant "this connection" to a client talking to the edge it came in on
+// - over psyche pub/sub, raddr already scopes which client gets which
+// payload, so the literal string was never meant to be read back. The
+// Prometheus scrape endpoint has no such scoping: it dumps every
+// registered series together, so every connection's gauge/counter would
+// otherwise render as the exact same label set. Substituting in the
+// metric's own raddr keeps each connection's series distinct.
+func promLabels(labels map[string]string, raddr string) map[string]string {
+	if labels == nil || raddr == "" {
+		return labels
+	}
+
+	resolved := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if v == "this" {
+			v = raddr
+		}
+		resolved[k] = v
+	}
+	return resolved
+}
+
 type Metrics struct {
-	edgesMu sync.Mutex
-	edges   map[string]psyche.Interface
+	sinksMu sync.Mutex
+	sinks   map[string]*sinkQueue
 
 	gaugesMu sync.Mutex
 	gauges   []*Gauge
 
+	countersMu sync.Mutex
+	counters   []*Counter
+
+	histogramsMu sync.Mutex
+	histograms   []*Histogram
+
+	// dropped counts messages discarded by enqueue because a sink's
+	// outbound queue was full.
+	dropped *Counter
+
+	wg sync.WaitGroup
+
 	ctx       context.Context
 	cancelCtx func()
 }
@@ -32,26 +127,82 @@ type Metrics struct {
 func New() *Metrics {
 	ctx, cancel := context.WithCancel(context.Background())
 	m := &Metrics{
-		edges:     map[string]psyche.Interface{},
+		sinks:     map[string]*sinkQueue{},
 		ctx:       ctx,
 		cancelCtx: cancel,
 	}
+	m.dropped = m.NewCounter("metrics_dropped_total", 0, 0, "")
 	return m
 }
 
-// pub publishes a payload to the edge with the given remote address.
-// if no raddr is provided, payload is publishes to all edges.
+// pub publishes a payload to the sink with the given remote address.
+// if no raddr is provided, payload is publishes to all sinks.
+//
+// Publication goes through each sink's bounded outbound queue rather than
+// calling Sink.Publish directly, so one slow sink can't block updates to
+// everyone else.
 func (m *Metrics) pub(raddr string, payload []byte) {
-	m.edgesMu.Lock()
-	defer m.edgesMu.Unlock()
+	m.sinksMu.Lock()
+	defer m.sinksMu.Unlock()
 
 	if raddr != "" {
-		if e, ok := m.edges[raddr]; ok {
-			e.Pub(metricsSubject, payload)
+		if sq, ok := m.sinks[raddr]; ok {
+			sq.enqueue(metricsSubject, payload)
 		}
 	} else {
-		for _, e := range m.edges {
-			e.Pub(metricsSubject, payload)
+		for _, sq := range m.sinks {
+			sq.enqueue(metricsSubject, payload)
+		}
+	}
+}
+
+// overloaded reports whether the outbound queue for raddr (or, if raddr
+// is "", any attached sink) is more than sinkOverloadPct full.
+func (m *Metrics) overloaded(raddr string) bool {
+	m.sinksMu.Lock()
+	defer m.sinksMu.Unlock()
+
+	if raddr != "" {
+		sq, ok := m.sinks[raddr]
+		return ok && sq.overloaded()
+	}
+
+	for _, sq := range m.sinks {
+		if sq.overloaded() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSink reports whether a sink is currently attached for raddr.
+func (m *Metrics) hasSink(raddr string) bool {
+	m.sinksMu.Lock()
+	defer m.sinksMu.Unlock()
+	_, ok := m.sinks[raddr]
+	return ok
+}
+
+// removeGauge unregisters g, if still registered, from m.gauges.
+func (m *Metrics) removeGauge(g *Gauge) {
+	m.gaugesMu.Lock()
+	defer m.gaugesMu.Unlock()
+	for i, x := range m.gauges {
+		if x == g {
+			m.gauges = append(m.gauges[:i], m.gauges[i+1:]...)
+			return
+		}
+	}
+}
+
+// removeCounter unregisters c, if still registered, from m.counters.
+func (m *Metrics) removeCounter(c *Counter) {
+	m.countersMu.Lock()
+	defer m.countersMu.Unlock()
+	for i, x := range m.counters {
+		if x == c {
+			m.counters = append(m.counters[:i], m.counters[i+1:]...)
+			return
 		}
 	}
 }
@@ -73,47 +224,183 @@ func (m *Metrics) pubMetrics(raddr string) {
 	}
 	m.gaugesMu.Unlock()
 
-	m.edgesMu.Lock()
-	m.edges[raddr].Pub(metricsSubject, []byte(b.String()))
-	m.edgesMu.Unlock()
-}
+	m.countersMu.Lock()
+	for _, c := range m.counters {
+		val := c.Val()
 
-func (m *Metrics) servePoller(edge psyche.Interface, raddr string) error {
-	defer edge.Close()
-	defer m.detach(edge, raddr)
+		if c.raddr == "" || c.raddr == raddr {
+			b.WriteString(c.StringWithVal(val))
+			b.WriteByte('\n')
+		}
 
-	var msg psyche.Message
-	for edge.ReadMsg(m.ctx, &msg) {
-		if bytes.Equal(bytes.ToUpper(msg.Payload), []byte("POLL")) {
-			m.pubMetrics(raddr)
+		c.oncePerDur.Reset()
+		c.oncePerDelta.Reset(val)
+	}
+	m.countersMu.Unlock()
+
+	m.histogramsMu.Lock()
+	for _, h := range m.histograms {
+		if h.raddr == "" || h.raddr == raddr {
+			b.WriteString(h.pubString())
+			b.WriteByte('\n')
 		}
+
+		h.oncePerDur.Reset()
 	}
+	m.histogramsMu.Unlock()
+
+	m.sinksMu.Lock()
+	m.sinks[raddr].enqueue(metricsSubject, []byte(b.String()))
+	m.sinksMu.Unlock()
+}
 
-	return edge.Err()
+// runPoller publishes a metrics snapshot to raddr every time sink's Poll
+// channel fires, until ctx is canceled, the Metrics is Closed, or that
+// channel is closed.
+func (m *Metrics) runPoller(ctx context.Context, sink Sink, raddr string) {
+	defer sink.Close()
+	defer m.detachSink(raddr, sink)
+
+	poll := sink.Poll()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.ctx.Done():
+			return
+		case _, ok := <-poll:
+			if !ok {
+				return
+			}
+			m.pubMetrics(raddr)
+		}
+	}
 }
 
-// Attach will subscribe to the metrics subject on the given
-// edge and publish metrics updates on it. Resources associated
-// with Attach will be freed when the given edge is closed.
+// Attach will subscribe to the metrics subject on the given psyche edge
+// and publish metrics updates on it. Resources associated with Attach
+// will be freed when the given edge is closed.
+//
+// It is equivalent to AttachContext(m.ctx, edge, remoteAddr), so the
+// poller is torn down when the Metrics itself is Closed.
 func (m *Metrics) Attach(edge psyche.Interface, remoteAddr string) {
-	m.edgesMu.Lock()
-	m.edges[remoteAddr] = edge
-	m.edgesMu.Unlock()
+	m.AttachContext(m.ctx, edge, remoteAddr)
+}
+
+// AttachContext is like Attach, but ties the poller's lifetime to ctx as
+// well as to the Metrics: whichever is canceled/closed first stops it.
+func (m *Metrics) AttachContext(ctx context.Context, edge psyche.Interface, remoteAddr string) {
+	m.AttachSink(ctx, newPsycheSink(ctx, edge), remoteAddr)
+}
 
-	edge.Sub(metricsSubject)
-	go m.servePoller(edge, remoteAddr)
+// AttachSink registers sink under raddr and publishes metrics updates
+// to it whenever sink's Poll channel fires, until ctx is canceled, the
+// Metrics is Closed, or sink closes its own Poll channel. Close blocks
+// until every sink attached this way has been torn down.
+func (m *Metrics) AttachSink(ctx context.Context, sink Sink, raddr string) {
+	m.sinksMu.Lock()
+	prev, ok := m.sinks[raddr]
+	m.sinks[raddr] = newSinkQueue(m, sink)
+	m.sinksMu.Unlock()
+
+	if ok {
+		prev.close()
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.runPoller(ctx, sink, raddr)
+	}()
 }
 
-func (m *Metrics) detach(edge psyche.Interface, raddr string) {
-	m.edgesMu.Lock()
-	defer m.edgesMu.Unlock()
-	if e, ok := m.edges[raddr]; ok && edge == e {
-		delete(m.edges, raddr)
+func (m *Metrics) detachSink(raddr string, sink Sink) {
+	m.sinksMu.Lock()
+	defer m.sinksMu.Unlock()
+	if sq, ok := m.sinks[raddr]; ok && sink == sq.sink {
+		delete(m.sinks, raddr)
+		sq.close()
 	}
 }
 
+// Close cancels every context-aware publication path owned by this
+// Metrics (pending OncePerDur timers, the Attach/AttachContext pollers)
+// and blocks until every poller and sink queue goroutine has returned.
+// It does not wait for a OncePerDur callback that is already running;
+// pending callbacks are dropped instead of run.
 func (m *Metrics) Close() {
 	m.cancelCtx()
+	m.wg.Wait()
+}
+
+// ServeHTTP renders every registered metric as a Prometheus/OpenMetrics
+// text exposition, including "# HELP"/"# TYPE" lines, so the Metrics can
+// be mounted directly as a scrape endpoint (conventionally at "/metrics").
+// It leaves the psyche POLL pub/sub path in pubMetrics untouched.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	m.gaugesMu.Lock()
+	gauges := make([]*Gauge, len(m.gauges))
+	copy(gauges, m.gauges)
+	m.gaugesMu.Unlock()
+
+	sort.Slice(gauges, func(i, j int) bool { return gauges[i].promName() < gauges[j].promName() })
+
+	seen := map[string]bool{}
+	for _, g := range gauges {
+		name := g.promName()
+		if !seen[name] {
+			seen[name] = true
+			fmt.Fprintf(w, "# HELP %s %s exported by fly-psyche.\n", name, name)
+			fmt.Fprintf(w, "# TYPE %s %s\n", name, g.Type())
+		}
+		fmt.Fprintln(w, g.PromStringWithVal(g.Val()))
+	}
+
+	m.countersMu.Lock()
+	counters := make([]*Counter, len(m.counters))
+	copy(counters, m.counters)
+	m.countersMu.Unlock()
+
+	sort.Slice(counters, func(i, j int) bool { return counters[i].promName() < counters[j].promName() })
+
+	seen = map[string]bool{}
+	for _, c := range counters {
+		name := c.promName()
+		if !seen[name] {
+			seen[name] = true
+			fmt.Fprintf(w, "# HELP %s %s exported by fly-psyche.\n", name, name)
+			fmt.Fprintf(w, "# TYPE %s %s\n", name, c.Type())
+		}
+		fmt.Fprintln(w, c.PromStringWithVal(c.Val()))
+	}
+
+	m.histogramsMu.Lock()
+	histograms := make([]*Histogram, len(m.histograms))
+	copy(histograms, m.histograms)
+	m.histogramsMu.Unlock()
+
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].promName() < histograms[j].promName() })
+
+	seen = map[string]bool{}
+	for _, h := range histograms {
+		name := h.promName()
+		if !seen[name] {
+			seen[name] = true
+			fmt.Fprintf(w, "# HELP %s %s exported by fly-psyche.\n", name, name)
+			fmt.Fprintf(w, "# TYPE %s %s\n", name, h.Type())
+		}
+		for _, line := range h.promLines() {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// Handler returns an http.Handler serving metrics in Prometheus text
+// exposition format, typically mounted at "/metrics" for scraping.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(m.ServeHTTP)
 }
 
 type Gauge struct {
@@ -123,6 +410,12 @@ type Gauge struct {
 	oncePerDelta *OncePerDelta
 	raddr        string
 	m            *Metrics
+
+	closed int32
+
+	ttlMu    sync.Mutex
+	ttl      time.Duration
+	ttlTimer *time.Timer
 }
 
 // NewGauge creates a new gauge.
@@ -148,15 +441,35 @@ func (g *Gauge) Val() int64 {
 	return atomic.LoadInt64(&g.val)
 }
 
+// Type reports the Prometheus/OpenMetrics type of this series.
+func (g *Gauge) Type() MetricType {
+	return TypeGauge
+}
+
+func (g *Gauge) promName() string {
+	name, _ := splitNameLabels(g.name)
+	return name
+}
+
 func (g *Gauge) StringWithVal(val int64) string {
 	return fmt.Sprintf("%s=%d", g.name, val)
 }
 
+// PromStringWithVal renders the gauge's value as a single Prometheus/
+// OpenMetrics text exposition sample, e.g. `bytes_sent{conn="1.2.3.4:5"} 42`.
+func (g *Gauge) PromStringWithVal(val int64) string {
+	name, labels := splitNameLabels(g.name)
+	return fmt.Sprintf("%s%s %d", name, formatPromLabels(promLabels(labels, g.raddr)), val)
+}
+
 func (g *Gauge) pub(v int64) {
 	g.m.pub(g.raddr, []byte(g.StringWithVal(v)))
 }
 
-// Add adds a value to the gauge.
+// Add adds a value to the gauge. The gauge's value is always updated, but
+// if the gauge's outbound queue(s) are over the overload threshold, the
+// publish is skipped entirely (as opposed to being queued and dropped) so
+// a backed-up consumer can't be kept busy catching up on no-op updates.
 //
 // Safe to call from multiple goroutines.
 func (g *Gauge) Add(n int64) {
@@ -164,8 +477,18 @@ func (g *Gauge) Add(n int64) {
 		return
 	}
 	val := atomic.AddInt64(&g.val, n)
+
+	g.ttlMu.Lock()
+	if g.ttlTimer != nil {
+		g.ttlTimer.Reset(g.ttl)
+	}
+	g.ttlMu.Unlock()
+
+	if g.m.overloaded(g.raddr) {
+		return
+	}
 	g.oncePerDelta.Do(val, func() {
-		g.oncePerDur.Do(func() {
+		g.oncePerDur.Do(g.m.ctx, func() {
 			g.pub(val)
 		})
 	})
@@ -179,10 +502,67 @@ func (g *Gauge) Dec() {
 	g.Add(-1)
 }
 
-func InFlightMiddleware(gauge *Gauge, next http.Handler) http.Handler {
+// SetTTL arms the gauge to auto-Close if no Add call refreshes it within
+// d, unless a sink is still attached for the gauge's raddr, in which case
+// it keeps rechecking every d instead. This is meant for ephemeral
+// per-connection gauges that might otherwise never be explicitly Closed.
+//
+// Safe to call from multiple goroutines.
+func (g *Gauge) SetTTL(d time.Duration) {
+	g.ttlMu.Lock()
+	defer g.ttlMu.Unlock()
+
+	g.ttl = d
+	if g.ttlTimer == nil {
+		g.ttlTimer = time.AfterFunc(d, g.checkTTL)
+	} else {
+		g.ttlTimer.Reset(d)
+	}
+}
+
+func (g *Gauge) checkTTL() {
+	g.ttlMu.Lock()
+	if g.raddr != "" && g.m.hasSink(g.raddr) {
+		g.ttlTimer.Reset(g.ttl)
+		g.ttlMu.Unlock()
+		return
+	}
+	g.ttlMu.Unlock()
+
+	g.Close()
+}
+
+// Close unregisters the gauge, after publishing one final value so
+// subscribers see where it ended up. Safe to call more than once, and
+// safe to call concurrently with Add.
+func (g *Gauge) Close() {
+	if !atomic.CompareAndSwapInt32(&g.closed, 0, 1) {
+		return
+	}
+
+	g.ttlMu.Lock()
+	if g.ttlTimer != nil {
+		g.ttlTimer.Stop()
+	}
+	g.ttlMu.Unlock()
+
+	g.pub(g.Val())
+	g.m.removeGauge(g)
+}
+
+// InFlightMiddleware tracks the number of requests currently being served
+// in gauge, and, if hist is non-nil, observes each request's duration in
+// seconds in hist so latency quantiles can be derived on scrape.
+func InFlightMiddleware(gauge *Gauge, hist *Histogram, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gauge.Inc()
-		defer gauge.Dec()
+		start := time.Now()
+		defer func() {
+			gauge.Dec()
+			if hist != nil {
+				hist.Observe(time.Since(start).Seconds())
+			}
+		}()
 		next.ServeHTTP(w, r)
 	})
 }
@@ -192,7 +572,7 @@ type metricsConn struct {
 	sentTotal,
 	receivedTotal,
 	sentThis,
-	receivedThis *Gauge
+	receivedThis *Counter
 }
 
 func (mc *metricsConn) Write(b []byte) (n int, err error) {
@@ -209,11 +589,26 @@ func (mc *metricsConn) Read(b []byte) (n int, err error) {
 	return
 }
 
+// Close closes the underlying connection and unregisters the
+// connection-scoped counters, so a long-running server doesn't leak one
+// pair of them per connection ever made.
+func (mc *metricsConn) Close() error {
+	err := mc.Conn.Close()
+	mc.sentThis.Close()
+	mc.receivedThis.Close()
+	return err
+}
+
+// connGaugeTTL bounds how long a per-connection counter can go without an
+// Add before it's auto-closed, as a backstop for connections whose
+// metricsConn.Close is never reached (e.g. a conn leaked by the caller).
+const connGaugeTTL = 5 * time.Minute
+
 type metricsListener struct {
 	net.Listener
 	sentTotal,
-	receivedTotal *Gauge
-	connGauges func(raddr string) (sent, received *Gauge)
+	receivedTotal *Counter
+	connGauges func(raddr string) (sent, received *Counter)
 }
 
 func (mln *metricsListener) Accept() (net.Conn, error) {
@@ -222,6 +617,8 @@ func (mln *metricsListener) Accept() (net.Conn, error) {
 	raddr := conn.RemoteAddr()
 
 	sent, received := mln.connGauges(raddr.String())
+	sent.SetTTL(connGaugeTTL)
+	received.SetTTL(connGaugeTTL)
 
 	return &metricsConn{
 		Conn:          conn,
@@ -232,9 +629,12 @@ func (mln *metricsListener) Accept() (net.Conn, error) {
 	}, err
 }
 
+// SentReceivedMiddleware wraps ln so that bytes written to and read from
+// every accepted connection are tallied in sentTotal/receivedTotal, as
+// well as in a per-connection pair of counters obtained from connGauges.
 func SentReceivedMiddleware(
-	sentTotal, receivedTotal *Gauge,
-	connGauges func(raddr string) (sent, received *Gauge),
+	sentTotal, receivedTotal *Counter,
+	connGauges func(raddr string) (sent, received *Counter),
 	ln net.Listener,
 ) net.Listener {
 	return &metricsListener{
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+// waitFor polls cond every 5ms until it returns true or timeout elapses,
+// failing the test in the latter case.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s", timeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCounterOverloadSkipsPublish(t *testing.T) {
+	m := New()
+	defer m.Close()
+
+	// Nobody drains sink.Published, so once its capacity (16) is
+	// exhausted the sinkQueue's drain goroutine wedges permanently on
+	// Publish and stops pulling off sq.queue.
+	sink := NewChanSink()
+	m.AttachSink(m.ctx, sink, "raddr")
+
+	// pub never blocks - enqueue drops the oldest queued message instead
+	// - so this saturates the queue past the overload threshold without
+	// needing to race the wedged drain goroutine.
+	for i := 0; i < sinkQueueSize*2; i++ {
+		m.pub("raddr", []byte("x"))
+	}
+	if !m.overloaded("raddr") {
+		t.Fatal("expected sink's queue to be overloaded after saturating it")
+	}
+	dropped := m.dropped.Val()
+
+	c := m.NewCounter("reqs", 0, 0, "raddr")
+
+	// Add must return immediately, and while overloaded must skip
+	// publishing entirely rather than queuing (and immediately dropping)
+	// another message.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10000; i++ {
+			c.Add(1)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Add blocked instead of skipping publish while overloaded")
+	}
+
+	if got := m.dropped.Val(); got != dropped {
+		t.Fatalf("Add published while overloaded: metrics_dropped_total went from %d to %d", dropped, got)
+	}
+
+	// Unwedge the sink so the deferred Close, which now waits for the
+	// drain goroutine, doesn't hang on the Publish call we stalled above.
+	go func() {
+		for range sink.Published {
+		}
+	}()
+}
+
+func TestCounterTTLAutoClose(t *testing.T) {
+	m := New()
+	defer m.Close()
+
+	c := m.NewCounter("conns", 0, 0, "raddr")
+	c.SetTTL(10 * time.Millisecond)
+
+	waitFor(t, time.Second, func() bool {
+		m.countersMu.Lock()
+		defer m.countersMu.Unlock()
+		for _, x := range m.counters {
+			if x == c {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestCounterTTLKeepsAliveWithSink(t *testing.T) {
+	m := New()
+	defer m.Close()
+
+	sink := NewChanSink()
+	go func() {
+		for range sink.Published {
+		}
+	}()
+	m.AttachSink(m.ctx, sink, "raddr")
+
+	c := m.NewCounter("conns", 0, 0, "raddr")
+	c.SetTTL(10 * time.Millisecond)
+
+	// Give checkTTL several chances to fire; as long as the sink stays
+	// attached it should keep rescheduling instead of closing.
+	time.Sleep(50 * time.Millisecond)
+
+	m.countersMu.Lock()
+	found := false
+	for _, x := range m.counters {
+		if x == c {
+			found = true
+			break
+		}
+	}
+	m.countersMu.Unlock()
+
+	if !found {
+		t.Fatal("counter was closed despite an attached sink")
+	}
+}
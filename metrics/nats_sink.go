@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes metrics on a fixed NATS subject and polls whenever
+// a message arrives on subject+".poll", letting a psyche-less client
+// trigger a snapshot the same way "POLL" does on a psyche edge.
+type NATSSink struct {
+	nc      *nats.Conn
+	subject string
+	sub     *nats.Subscription
+
+	mu     sync.Mutex
+	closed bool
+	poll   chan struct{}
+}
+
+// NewNATSSink subscribes to subject+".poll" on nc and returns a sink that
+// publishes metrics to subject. The caller keeps ownership of nc; Close
+// only tears down the subscription.
+func NewNATSSink(nc *nats.Conn, subject string) (*NATSSink, error) {
+	s := &NATSSink{
+		nc:      nc,
+		subject: subject,
+		poll:    make(chan struct{}),
+	}
+
+	sub, err := nc.Subscribe(subject+".poll", func(*nats.Msg) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closed {
+			return
+		}
+
+		select {
+		case s.poll <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.sub = sub
+
+	return s, nil
+}
+
+// Publish ignores subject and always publishes to the NATS subject this
+// sink was constructed with.
+func (s *NATSSink) Publish(subject string, payload []byte) error {
+	return s.nc.Publish(s.subject, payload)
+}
+
+func (s *NATSSink) Poll() <-chan struct{} {
+	return s.poll
+}
+
+func (s *NATSSink) Close() {
+	s.sub.Drain()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.poll)
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/abstract-p2p/go-psyche"
+)
+
+// psycheSink adapts a psyche.Interface edge to the Sink interface: it
+// publishes via edge.Pub, and polls whenever the edge receives a "POLL"
+// message on the metrics subject.
+type psycheSink struct {
+	edge psyche.Interface
+	ctx  context.Context
+
+	poll chan struct{}
+}
+
+// newPsycheSink subscribes edge to the metrics subject and starts the
+// goroutine that turns incoming "POLL" messages into Poll() events. It
+// runs until ctx is canceled or edge.ReadMsg otherwise returns false.
+func newPsycheSink(ctx context.Context, edge psyche.Interface) *psycheSink {
+	s := &psycheSink{
+		edge: edge,
+		ctx:  ctx,
+		poll: make(chan struct{}),
+	}
+
+	edge.Sub(metricsSubject)
+	go s.readLoop()
+
+	return s
+}
+
+func (s *psycheSink) readLoop() {
+	defer s.edge.Close()
+	defer close(s.poll)
+
+	var msg psyche.Message
+	for s.edge.ReadMsg(s.ctx, &msg) {
+		if bytes.Equal(bytes.ToUpper(msg.Payload), []byte("POLL")) {
+			select {
+			case s.poll <- struct{}{}:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (s *psycheSink) Publish(subject string, payload []byte) error {
+	s.edge.Pub(subject, payload)
+	return nil
+}
+
+func (s *psycheSink) Poll() <-chan struct{} {
+	return s.poll
+}
+
+func (s *psycheSink) Close() {
+	s.edge.Close()
+}
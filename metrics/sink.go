@@ -0,0 +1,20 @@
+package metrics
+
+// Sink is a destination metrics can be published to and polled from. It
+// abstracts over the transport so Metrics itself only ever deals in
+// subjects and payload bytes. A psyche edge is one implementation;
+// NATSSink, StatsDSink and ChanSink are others.
+type Sink interface {
+	// Publish sends payload under subject. What subject means, and
+	// whether it's used at all, is transport-specific.
+	Publish(subject string, payload []byte) error
+
+	// Poll returns a channel that receives a value every time the sink
+	// wants a fresh snapshot of the metrics scoped to it. The channel is
+	// closed when the sink itself is done, which also tears down the
+	// Metrics-side poller attached to it.
+	Poll() <-chan struct{}
+
+	// Close releases the sink's resources.
+	Close()
+}
@@ -0,0 +1,75 @@
+package metrics
+
+const (
+	// sinkQueueSize bounds how many outbound messages may be queued for
+	// a single sink before the oldest queued message is dropped.
+	sinkQueueSize = 1024
+
+	// sinkOverloadPct is the percentage of sinkQueueSize above which a
+	// sink is considered overloaded.
+	sinkOverloadPct = 80
+)
+
+type queuedMsg struct {
+	subject string
+	payload []byte
+}
+
+// sinkQueue owns a single Sink's outbound queue and the goroutine that
+// drains it, so a slow sink blocks only its own queue instead of holding
+// up publication to every other sink.
+type sinkQueue struct {
+	sink  Sink
+	queue chan queuedMsg
+	m     *Metrics
+}
+
+func newSinkQueue(m *Metrics, sink Sink) *sinkQueue {
+	sq := &sinkQueue{
+		sink:  sink,
+		queue: make(chan queuedMsg, sinkQueueSize),
+		m:     m,
+	}
+
+	m.wg.Add(1)
+	go sq.run()
+
+	return sq
+}
+
+func (sq *sinkQueue) run() {
+	defer sq.m.wg.Done()
+
+	for msg := range sq.queue {
+		sq.sink.Publish(msg.subject, msg.payload)
+	}
+}
+
+// enqueue queues payload for publication, dropping the oldest queued
+// message and counting it in metrics_dropped_total if the queue is full.
+func (sq *sinkQueue) enqueue(subject string, payload []byte) {
+	for {
+		select {
+		case sq.queue <- queuedMsg{subject, payload}:
+			return
+		default:
+		}
+
+		select {
+		case <-sq.queue:
+			sq.m.dropped.addSilent(1)
+		default:
+		}
+	}
+}
+
+// overloaded reports whether the queue is more than sinkOverloadPct full.
+func (sq *sinkQueue) overloaded() bool {
+	return len(sq.queue)*100 >= sinkOverloadPct*cap(sq.queue)
+}
+
+// close makes the drain goroutine exit once it has published any
+// messages still queued.
+func (sq *sinkQueue) close() {
+	close(sq.queue)
+}
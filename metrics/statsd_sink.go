@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// StatsDSink translates each published "name=value" line into a StatsD
+// gauge line ("name:value|g") and fires it at a UDP endpoint. StatsD has
+// no poll concept, so Poll never receives.
+type StatsDSink struct {
+	conn *net.UDPConn
+	poll chan struct{}
+}
+
+// NewStatsDSink dials addr ("host:port") over UDP to send StatsD lines.
+func NewStatsDSink(addr string) (*StatsDSink, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsDSink{conn: conn, poll: make(chan struct{})}, nil
+}
+
+// Publish splits payload into the "name=value" lines used throughout
+// this package and sends one StatsD gauge line per value.
+//
+// The Sink interface carries no metric type, so every series - Gauge,
+// Counter, or a Histogram's bucket/sum/count lines alike - is emitted as
+// a StatsD gauge ("|g") rather than the more accurate "|c"/"|h". Getting
+// Counter/Histogram series labeled "|c"/"|h" would mean threading a
+// MetricType through Sink.Publish.
+func (s *StatsDSink) Publish(subject string, payload []byte) error {
+	for _, line := range strings.Split(strings.TrimSpace(string(payload)), "\n") {
+		if line == "" {
+			continue
+		}
+
+		nameVal := strings.SplitN(line, "=", 2)
+		if len(nameVal) != 2 {
+			continue
+		}
+
+		name, _ := splitNameLabels(nameVal[0])
+		if _, err := fmt.Fprintf(s.conn, "%s:%s|g", name, nameVal[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StatsDSink) Poll() <-chan struct{} {
+	return s.poll
+}
+
+func (s *StatsDSink) Close() {
+	close(s.poll)
+	s.conn.Close()
+}
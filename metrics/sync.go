@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -14,8 +15,9 @@ type OncePerDur struct {
 	// If the timer is running then pending will be non-nil, but
 	// the converse is not always true: if pending is non-nil then
 	// either the timer or the function runner is running.
-	timer   *time.Timer
-	pending func()
+	timer      *time.Timer
+	pending    func()
+	pendingCtx context.Context
 
 	// isRunning is true if and only if the function runner is running.
 	// pending may or may not be nil, regardless of whether isRunning is
@@ -29,13 +31,21 @@ func NewOncePerDur(dur time.Duration) *OncePerDur {
 	}
 }
 
-func (op *OncePerDur) Do(f func()) {
+// Do schedules f to run at most once per dur. If ctx is canceled before f
+// runs, either while waiting on the timer or queued behind another pending
+// f, it is dropped instead of running.
+func (op *OncePerDur) Do(ctx context.Context, f func()) {
+	if ctx.Err() != nil {
+		return
+	}
+
 	op.mu.Lock()
 
 	// if timer or timer handler is already running,
 	// just update the pending func
 	if op.pending != nil || op.isRunning {
 		op.pending = f
+		op.pendingCtx = ctx
 		op.mu.Unlock()
 		return
 	}
@@ -45,11 +55,12 @@ func (op *OncePerDur) Do(f func()) {
 	next := op.last.Add(op.dur)
 	if now.Before(next) {
 		op.pending = f
+		op.pendingCtx = ctx
 
 		if op.timer == nil {
-			op.timer = time.AfterFunc(now.Sub(next), op.handleTimer)
+			op.timer = time.AfterFunc(next.Sub(now), op.handleTimer)
 		} else {
-			op.timer.Reset(op.dur)
+			op.timer.Reset(next.Sub(now))
 		}
 
 		op.mu.Unlock()
@@ -72,10 +83,22 @@ func (op *OncePerDur) Reset() {
 		op.timer.Stop()
 	}
 	op.pending = nil
+	op.pendingCtx = nil
 	op.last = time.Now()
 	op.mu.Unlock()
 }
 
+// pendingCanceled reports whether the currently pending func's context
+// has been canceled, and if so clears it. Callers must hold op.mu.
+func (op *OncePerDur) pendingCanceled() bool {
+	if op.pendingCtx != nil && op.pendingCtx.Err() != nil {
+		op.pending = nil
+		op.pendingCtx = nil
+		return true
+	}
+	return false
+}
+
 func (op *OncePerDur) runner(f func()) {
 	f()
 
@@ -94,9 +117,16 @@ func (op *OncePerDur) runner(f func()) {
 			return
 		}
 
+		if op.pendingCanceled() {
+			op.isRunning = false
+			op.mu.Unlock()
+			return
+		}
+
 		op.last = time.Now()
 		f := op.pending
 		op.pending = nil
+		op.pendingCtx = nil
 
 		op.mu.Unlock()
 
@@ -107,7 +137,7 @@ func (op *OncePerDur) runner(f func()) {
 func (op *OncePerDur) handleTimer() {
 	op.mu.Lock()
 
-	if op.pending == nil {
+	if op.pending == nil || op.pendingCanceled() {
 		op.mu.Unlock()
 		return
 	}
@@ -115,6 +145,7 @@ func (op *OncePerDur) handleTimer() {
 	op.last = time.Now()
 	f := op.pending
 	op.pending = nil
+	op.pendingCtx = nil
 	op.isRunning = true
 
 	op.mu.Unlock()